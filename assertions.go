@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// assertionFailure is returned by evaluateAll to distinguish a failed
+// assertion from a transport-level error (connection refused, timeout),
+// which reporters such as JUnit need to tell apart.
+type assertionFailure struct {
+	msg string
+}
+
+func (e *assertionFailure) Error() string {
+	return e.msg
+}
+
+// evaluate checks a against the full HTTP response (status, headers, body)
+// and how long the request took, returning an error describing why the
+// assertion failed, or nil if it held.
+func (a assertion) evaluate(resp *http.Response, body []byte, responseTime time.Duration) error {
+	switch a.Kind {
+	case assertionBodyRegex:
+		re, err := regexp.Compile(a.BodyRegex)
+		if err != nil {
+			return fmt.Errorf("body_regex '%s' cannot be compiled: %v", a.BodyRegex, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body_regex '%s' did not match the response body", a.BodyRegex)
+		}
+
+	case assertionStatus:
+		for _, want := range a.Statuses {
+			if resp.StatusCode == want {
+				return nil
+			}
+		}
+		return fmt.Errorf("status %d not in expected %v", resp.StatusCode, a.Statuses)
+
+	case assertionHeader:
+		value := resp.Header.Get(a.Header.Name)
+		switch {
+		case a.Header.Regex != "":
+			re, err := regexp.Compile(a.Header.Regex)
+			if err != nil {
+				return fmt.Errorf("header '%s' regex '%s' cannot be compiled: %v", a.Header.Name, a.Header.Regex, err)
+			}
+			if !re.MatchString(value) {
+				return fmt.Errorf("header '%s' value '%s' did not match regex '%s'", a.Header.Name, value, a.Header.Regex)
+			}
+		case a.Header.Equals != "":
+			if value != a.Header.Equals {
+				return fmt.Errorf("header '%s' value '%s' did not equal '%s'", a.Header.Name, value, a.Header.Equals)
+			}
+		}
+
+	case assertionJSONPath:
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return fmt.Errorf("jsonpath '%s': response is not JSON: %v", a.JSONPath.Path, err)
+		}
+		value, err := jsonPathLookup(data, strings.TrimPrefix(a.JSONPath.Path, "$."))
+		if err != nil {
+			return fmt.Errorf("jsonpath '%s': %v", a.JSONPath.Path, err)
+		}
+		if a.JSONPath.Equals != nil && fmt.Sprintf("%v", value) != *a.JSONPath.Equals {
+			return fmt.Errorf("jsonpath '%s' value '%v' did not equal '%s'", a.JSONPath.Path, value, *a.JSONPath.Equals)
+		}
+
+	case assertionJSONSchema:
+		schemaBytes := a.JSONSchema.Schema
+		if a.JSONSchema.File != "" {
+			b, err := os.ReadFile(a.JSONSchema.File)
+			if err != nil {
+				return fmt.Errorf("json_schema: reading '%s': %v", a.JSONSchema.File, err)
+			}
+			schemaBytes = b
+		}
+
+		var schema miniSchema
+		if err := json.Unmarshal(schemaBytes, &schema); err != nil {
+			return fmt.Errorf("json_schema: invalid schema: %v", err)
+		}
+
+		var instance interface{}
+		if err := json.Unmarshal(body, &instance); err != nil {
+			return fmt.Errorf("json_schema: response is not JSON: %v", err)
+		}
+
+		if errs := validateSchema(instance, schema, ""); len(errs) > 0 {
+			return fmt.Errorf("json_schema: %s", strings.Join(errs, "; "))
+		}
+
+	case assertionResponseTimeMax:
+		if ms := responseTime.Milliseconds(); ms > a.ResponseTimeMaxMs {
+			return fmt.Errorf("response time %dms exceeded response_time_max_ms %d", ms, a.ResponseTimeMaxMs)
+		}
+	}
+
+	return nil
+}
+
+// evaluateAll runs every assertion against resp/body/responseTime and
+// returns an error listing every assertion that failed, or nil if all held.
+func evaluateAll(assertions []assertion, resp *http.Response, body []byte, responseTime time.Duration) error {
+	var failures []string
+	for _, a := range assertions {
+		if err := a.evaluate(resp, body, responseTime); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &assertionFailure{msg: fmt.Sprintf("%d assertion(s) failed: %s", len(failures), strings.Join(failures, "; "))}
+}
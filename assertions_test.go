@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestEvaluateAllAllPass(t *testing.T) {
+	assertions := []assertion{
+		{Kind: assertionStatus, Statuses: []int{200}},
+		{Kind: assertionBodyRegex, BodyRegex: "hello"},
+	}
+	resp := &http.Response{StatusCode: 200}
+
+	if err := evaluateAll(assertions, resp, []byte("hello world"), time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEvaluateAllCollectsFailures(t *testing.T) {
+	assertions := []assertion{
+		{Kind: assertionStatus, Statuses: []int{200}},
+		{Kind: assertionBodyRegex, BodyRegex: "goodbye"},
+	}
+	resp := &http.Response{StatusCode: 404}
+
+	err := evaluateAll(assertions, resp, []byte("hello world"), time.Millisecond)
+	if err == nil {
+		t.Fatal("expecting error, got none")
+	}
+	if !isAssertionFailure(err) {
+		t.Errorf("expected an assertionFailure, got %T", err)
+	}
+}
+
+func TestEvaluateAllResponseTimeMax(t *testing.T) {
+	assertions := []assertion{
+		{Kind: assertionResponseTimeMax, ResponseTimeMaxMs: 10},
+	}
+	resp := &http.Response{StatusCode: 200}
+
+	if err := evaluateAll(assertions, resp, nil, 50*time.Millisecond); err == nil {
+		t.Error("expecting error for exceeded response_time_max_ms, got none")
+	}
+	if err := evaluateAll(assertions, resp, nil, 5*time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
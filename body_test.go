@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBuildRequestBodyRaw(t *testing.T) {
+	r := request{BodySpec: &bodySpec{Kind: bodyRaw, Raw: "hello"}}
+
+	reader, contentType, closer, err := buildRequestBody(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if closer != nil {
+		t.Error("expected no closer for a raw body")
+	}
+	if contentType != "" {
+		t.Errorf("expected no content type, got '%s'", contentType)
+	}
+	b, _ := io.ReadAll(reader)
+	if string(b) != "hello" {
+		t.Errorf("expected 'hello', got '%s'", b)
+	}
+}
+
+func TestBuildRequestBodyForm(t *testing.T) {
+	r := request{BodySpec: &bodySpec{Kind: bodyForm, Form: map[string]string{"a": "1"}}}
+
+	reader, contentType, _, err := buildRequestBody(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("unexpected content type '%s'", contentType)
+	}
+	b, _ := io.ReadAll(reader)
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		t.Fatalf("failed parsing encoded form: %v", err)
+	}
+	if values.Get("a") != "1" {
+		t.Errorf("expected a=1, got '%s'", values.Get("a"))
+	}
+}
+
+func TestBuildRequestBodyLegacyPlainBody(t *testing.T) {
+	r := request{Body: "legacy body"}
+
+	reader, contentType, _, err := buildRequestBody(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contentType != "" {
+		t.Errorf("expected no content type, got '%s'", contentType)
+	}
+	b, _ := io.ReadAll(reader)
+	if string(b) != "legacy body" {
+		t.Errorf("expected 'legacy body', got '%s'", b)
+	}
+}
+
+// TestBuildRequestBodyMultipartField ensures a plain (non-file) multipart
+// field is encoded under its name with a boundary matching the returned
+// Content-Type.
+func TestBuildRequestBodyMultipartField(t *testing.T) {
+	r := request{BodySpec: &bodySpec{
+		Kind: bodyMultipart,
+		Multipart: []multipartField{
+			{Name: "field", Value: "plain"},
+		},
+	}}
+
+	reader, contentType, _, err := buildRequestBody(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		t.Fatalf("failed parsing content type '%s': %v", contentType, err)
+	}
+
+	mr := multipart.NewReader(reader, params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("failed reading multipart part: %v", err)
+	}
+	if part.FormName() != "field" {
+		t.Errorf("expected field name 'field', got '%s'", part.FormName())
+	}
+}
+
+func TestCreateMultipartFilePartEscapesQuotes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	part := multipartField{Name: `file"; evil="x`, Filename: "x.bin"}
+	if _, err := createMultipartFilePart(w, part); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Close()
+
+	if strings.Contains(buf.String(), `evil="x"`) {
+		t.Errorf("unescaped quote let an extra parameter leak into the header: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `name="file\"; evil=\"x"`) {
+		t.Errorf("expected escaped name in Content-Disposition, got: %s", buf.String())
+	}
+}
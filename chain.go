@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// chainNode wraps a single request with the bookkeeping needed to run it as
+// part of a dependency graph: a signal channel other nodes can wait on, and
+// the outcome once it has fired.
+type chainNode struct {
+	req     request
+	done    chan struct{}     // closed once this node has run (or been skipped)
+	success bool              // whether the request succeeded
+	vars    map[string]string // variables extracted from this node's response
+}
+
+// buildChain indexes requests by Name and validates the DependsOn graph,
+// returning an error for an unknown dependency or a dependency cycle. Name
+// is only required to be unique among requests that actually use it: it is
+// how a dependent names its parent in DependsOn, so two requests sharing a
+// non-empty Name would make that reference ambiguous. An empty Name can't
+// be depended on in the first place, so unnamed requests are free to
+// collide with one another (and ran that way before chaining existed).
+func buildChain(requests []request) (map[string]*chainNode, error) {
+	nodes := make(map[string]*chainNode, len(requests))
+	for i, r := range requests {
+		key := r.Name
+		if key == "" {
+			key = fmt.Sprintf("\x00unnamed#%d", i)
+		} else if _, dup := nodes[key]; dup {
+			return nil, fmt.Errorf("duplicate request name '%s'", r.Name)
+		}
+		nodes[key] = &chainNode{req: r, done: make(chan struct{})}
+	}
+
+	for name, n := range nodes {
+		for _, dep := range n.req.DependsOn {
+			if _, ok := nodes[dep]; !ok {
+				return nil, fmt.Errorf("request '%s' depends on unknown request '%s'", name, dep)
+			}
+		}
+	}
+
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := make(map[string]int, len(nodes))
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch color[name] {
+		case gray:
+			return fmt.Errorf("dependency cycle detected at '%s'", name)
+		case black:
+			return nil
+		}
+		color[name] = gray
+		for _, dep := range nodes[name].req.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		color[name] = black
+		return nil
+	}
+
+	for name := range nodes {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+// runChain fans out every node in the graph. Nodes without dependencies run
+// immediately; dependents block on their parents' done channel and are
+// short-circuited if a parent did not succeed. Results are sent to c as they
+// become available, and c is closed once every node has run.
+func runChain(nodes map[string]*chainNode, c chan result, sessions map[string]*session, defaultClient *http.Client) {
+	var wg sync.WaitGroup
+	wg.Add(len(nodes))
+
+	for _, n := range nodes {
+		go runNode(n, nodes, c, sessions, defaultClient, &wg)
+	}
+
+	go func() {
+		wg.Wait()
+		close(c)
+	}()
+}
+
+// runNode waits for n's dependencies, merges their extracted variables,
+// interpolates and sends n's request, then records the outcome for any
+// nodes depending on n.
+func runNode(n *chainNode, nodes map[string]*chainNode, c chan result, sessions map[string]*session, defaultClient *http.Client, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(n.done)
+
+	vars := map[string]string{}
+	for _, dep := range n.req.DependsOn {
+		parent := nodes[dep]
+		<-parent.done
+
+		if !parent.success {
+			c <- result{Request: n.req, Error: fmt.Errorf("skipped: parent %s failed", dep)}
+			return
+		}
+		for k, v := range parent.vars {
+			vars[k] = v
+		}
+	}
+
+	req, err := interpolateRequest(n.req, vars)
+	if err != nil {
+		c <- result{Request: n.req, Error: err}
+		return
+	}
+
+	sess, err := resolveSession(req, sessions, defaultClient)
+	if err != nil {
+		c <- result{Request: n.req, Error: err}
+		return
+	}
+
+	res := send(sess, req)
+	n.vars = res.Vars
+	n.success = res.Error == nil
+	c <- res
+}
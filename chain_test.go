@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestBuildChainDuplicateName(t *testing.T) {
+	requests := []request{
+		{Name: "a"},
+		{Name: "a"},
+	}
+
+	_, err := buildChain(requests)
+	if err == nil {
+		t.Fatal("expecting error for duplicate request name, got none")
+	}
+}
+
+// Unnamed requests can't be depended on, so they're allowed to collide:
+// a directory full of standalone, unnamed request files should keep working
+// exactly as it did before chaining existed.
+func TestBuildChainBlankNamesDoNotCollide(t *testing.T) {
+	requests := []request{
+		{Name: ""},
+		{Name: ""},
+	}
+
+	nodes, err := buildChain(requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("expected 2 distinct nodes for two unnamed requests, got %d", len(nodes))
+	}
+}
+
+func TestBuildChainUnknownDependency(t *testing.T) {
+	requests := []request{
+		{Name: "a", DependsOn: []string{"missing"}},
+	}
+
+	_, err := buildChain(requests)
+	if err == nil {
+		t.Fatal("expecting error for unknown dependency, got none")
+	}
+}
+
+func TestBuildChainCycle(t *testing.T) {
+	requests := []request{
+		{Name: "a", DependsOn: []string{"b"}},
+		{Name: "b", DependsOn: []string{"a"}},
+	}
+
+	_, err := buildChain(requests)
+	if err == nil {
+		t.Fatal("expecting error for dependency cycle, got none")
+	}
+}
+
+func TestBuildChainNormal(t *testing.T) {
+	requests := []request{
+		{Name: "a"},
+		{Name: "b", DependsOn: []string{"a"}},
+	}
+
+	nodes, err := buildChain(requests)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("expected 2 nodes, got %d", len(nodes))
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// extractValue evaluates a single extractor expression against the given
+// response and body, and returns the extracted value as a string.
+//
+// Supported forms:
+//
+//	$.field.sub     JSONPath-style dotted lookup into the JSON response body
+//	header:Name     the value of the named response header
+//	status          the numeric HTTP status code, as a string
+//	regex:<expr>    the first capture group of <expr> matched against the body
+func extractValue(spec string, resp *http.Response, body []byte) (string, error) {
+	switch {
+	case spec == "status":
+		return strconv.Itoa(resp.StatusCode), nil
+
+	case strings.HasPrefix(spec, "header:"):
+		name := strings.TrimPrefix(spec, "header:")
+		return resp.Header.Get(name), nil
+
+	case strings.HasPrefix(spec, "regex:"):
+		expr := strings.TrimPrefix(spec, "regex:")
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex extractor '%s': %v", expr, err)
+		}
+		m := re.FindSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("regex extractor '%s' found no match", expr)
+		}
+		if len(m) < 2 {
+			return "", fmt.Errorf("regex extractor '%s' has no capture group", expr)
+		}
+		return string(m[1]), nil
+
+	case strings.HasPrefix(spec, "$."):
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", fmt.Errorf("jsonpath extractor '%s': response is not JSON: %v", spec, err)
+		}
+		v, err := jsonPathLookup(data, strings.TrimPrefix(spec, "$."))
+		if err != nil {
+			return "", fmt.Errorf("jsonpath extractor '%s': %v", spec, err)
+		}
+		return fmt.Sprintf("%v", v), nil
+	}
+
+	return "", fmt.Errorf("unrecognized extractor '%s'", spec)
+}
+
+// jsonPathLookup walks a dot-separated path (e.g. "access_token" or
+// "user.id") into a decoded JSON value.
+func jsonPathLookup(data interface{}, path string) (interface{}, error) {
+	cur := data
+	for _, field := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("field '%s' not found: not an object", field)
+		}
+		v, ok := m[field]
+		if !ok {
+			return nil, fmt.Errorf("field '%s' not found", field)
+		}
+		cur = v
+	}
+	return cur, nil
+}
+
+// extractAll evaluates every extractor in extractors against resp/body and
+// returns the resulting name->value map.
+func extractAll(extractors map[string]string, resp *http.Response, body []byte) (map[string]string, error) {
+	if len(extractors) == 0 {
+		return nil, nil
+	}
+
+	vars := make(map[string]string, len(extractors))
+	for name, spec := range extractors {
+		v, err := extractValue(spec, resp, body)
+		if err != nil {
+			return nil, fmt.Errorf("extracting '%s': %v", name, err)
+		}
+		vars[name] = v
+	}
+	return vars, nil
+}
+
+// templateFuncs are the functions made available to templates interpolated
+// into request fields.
+var templateFuncs = template.FuncMap{
+	"env": os.Getenv,
+}
+
+// interpolate renders s as a text/template using vars as the dot context,
+// with the templateFuncs helpers available.
+func interpolate(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("interpolate").Funcs(templateFuncs).Parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// interpolateRequest returns a copy of r with vars interpolated into its
+// URL, Headers, Body and Assertions.
+func interpolateRequest(r request, vars map[string]string) (request, error) {
+	var err error
+
+	if r.URL, err = interpolate(r.URL, vars); err != nil {
+		return request{}, fmt.Errorf("interpolating url: %v", err)
+	}
+	if r.Body, err = interpolate(r.Body, vars); err != nil {
+		return request{}, fmt.Errorf("interpolating body: %v", err)
+	}
+
+	headers := make([]header, len(r.Headers))
+	for i, h := range r.Headers {
+		rendered, err := interpolate(string(h), vars)
+		if err != nil {
+			return request{}, fmt.Errorf("interpolating header '%s': %v", h, err)
+		}
+		headers[i] = header(rendered)
+	}
+	r.Headers = headers
+
+	assertions := make([]assertion, len(r.Assertions))
+	for i, a := range r.Assertions {
+		rendered, err := interpolateAssertion(a, vars)
+		if err != nil {
+			return request{}, fmt.Errorf("interpolating assertion '%s': %v", a, err)
+		}
+		assertions[i] = rendered
+	}
+	r.Assertions = assertions
+
+	if r.BodySpec != nil {
+		spec, err := interpolateBodySpec(*r.BodySpec, vars)
+		if err != nil {
+			return request{}, fmt.Errorf("interpolating body: %v", err)
+		}
+		r.BodySpec = &spec
+	}
+
+	return r, nil
+}
+
+// interpolateBodySpec interpolates vars into a raw body, the values of a form
+// body, and the values and filenames of multipart parts, so a chained
+// request can feed an extracted variable (e.g. a token) into a form, raw or
+// multipart body. A file body's path and multipart part names, file paths
+// and content types are left as they are.
+func interpolateBodySpec(b bodySpec, vars map[string]string) (bodySpec, error) {
+	var err error
+
+	switch b.Kind {
+	case bodyRaw:
+		if b.Raw, err = interpolate(b.Raw, vars); err != nil {
+			return bodySpec{}, err
+		}
+
+	case bodyForm:
+		form := make(map[string]string, len(b.Form))
+		for k, v := range b.Form {
+			if form[k], err = interpolate(v, vars); err != nil {
+				return bodySpec{}, err
+			}
+		}
+		b.Form = form
+
+	case bodyMultipart:
+		multipart := make([]multipartField, len(b.Multipart))
+		for i, part := range b.Multipart {
+			if part.Value, err = interpolate(part.Value, vars); err != nil {
+				return bodySpec{}, err
+			}
+			if part.Filename, err = interpolate(part.Filename, vars); err != nil {
+				return bodySpec{}, err
+			}
+			multipart[i] = part
+		}
+		b.Multipart = multipart
+	}
+
+	return b, nil
+}
+
+// interpolateAssertion interpolates vars into the string-valued fields of
+// body_regex, header and jsonpath assertions. Other kinds, including a
+// json_schema file path, are returned unchanged.
+func interpolateAssertion(a assertion, vars map[string]string) (assertion, error) {
+	var err error
+
+	switch a.Kind {
+	case assertionBodyRegex:
+		if a.BodyRegex, err = interpolate(a.BodyRegex, vars); err != nil {
+			return assertion{}, err
+		}
+	case assertionHeader:
+		if a.Header.Name, err = interpolate(a.Header.Name, vars); err != nil {
+			return assertion{}, err
+		}
+		if a.Header.Regex, err = interpolate(a.Header.Regex, vars); err != nil {
+			return assertion{}, err
+		}
+		if a.Header.Equals, err = interpolate(a.Header.Equals, vars); err != nil {
+			return assertion{}, err
+		}
+	case assertionJSONPath:
+		if a.JSONPath.Path, err = interpolate(a.JSONPath.Path, vars); err != nil {
+			return assertion{}, err
+		}
+		if a.JSONPath.Equals != nil {
+			rendered, err := interpolate(*a.JSONPath.Equals, vars)
+			if err != nil {
+				return assertion{}, err
+			}
+			a.JSONPath.Equals = &rendered
+		}
+	}
+
+	return a, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestExtractValue(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 201,
+		Header:     http.Header{"X-Request-Id": []string{"abc123"}},
+	}
+	body := []byte(`{"token": "deadbeef", "user": {"id": 7}}`)
+
+	tests := []struct {
+		spec     string
+		expected string
+	}{
+		{"status", "201"},
+		{"header:X-Request-Id", "abc123"},
+		{"regex:token\": \"([a-z0-9]+)\"", "deadbeef"},
+		{"$.token", "deadbeef"},
+		{"$.user.id", "7"},
+	}
+
+	for _, test := range tests {
+		actual, err := extractValue(test.spec, resp, body)
+		if err != nil {
+			t.Errorf("spec '%s': unexpected error: %v", test.spec, err)
+			continue
+		}
+		if actual != test.expected {
+			t.Errorf("spec '%s': expected '%s', got '%s'", test.spec, test.expected, actual)
+		}
+	}
+}
+
+func TestExtractValueErrors(t *testing.T) {
+	resp := &http.Response{StatusCode: 200}
+	body := []byte(`not json`)
+
+	if _, err := extractValue("$.token", resp, body); err == nil {
+		t.Error("expecting error for jsonpath against non-JSON body, got none")
+	}
+	if _, err := extractValue("bogus", resp, body); err == nil {
+		t.Error("expecting error for unrecognized extractor, got none")
+	}
+}
+
+func TestExtractAll(t *testing.T) {
+	resp := &http.Response{StatusCode: 200}
+	body := []byte(`{"token": "deadbeef"}`)
+
+	vars, err := extractAll(map[string]string{"tok": "$.token"}, resp, body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vars["tok"] != "deadbeef" {
+		t.Errorf("expected 'deadbeef', got '%s'", vars["tok"])
+	}
+
+	if vars, err := extractAll(nil, resp, body); err != nil || vars != nil {
+		t.Errorf("expected (nil, nil) for no extractors, got (%v, %v)", vars, err)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string]string{"token": "deadbeef"}
+
+	actual, err := interpolate("Bearer {{.token}}", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actual != "Bearer deadbeef" {
+		t.Errorf("expected 'Bearer deadbeef', got '%s'", actual)
+	}
+
+	if actual, err := interpolate("no templates here", vars); err != nil || actual != "no templates here" {
+		t.Errorf("expected passthrough for a string without templates, got '%s' / %v", actual, err)
+	}
+}
+
+func TestInterpolateRequestURLBodyHeaders(t *testing.T) {
+	vars := map[string]string{"token": "deadbeef", "id": "7"}
+	r := request{
+		URL:     "http://example.org/users/{{.id}}",
+		Body:    "auth={{.token}}",
+		Headers: []header{header("Authorization: Bearer {{.token}}")},
+	}
+
+	rendered, err := interpolateRequest(r, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rendered.URL != "http://example.org/users/7" {
+		t.Errorf("expected interpolated url, got '%s'", rendered.URL)
+	}
+	if rendered.Body != "auth=deadbeef" {
+		t.Errorf("expected interpolated body, got '%s'", rendered.Body)
+	}
+	if rendered.Headers[0].Value() != "Bearer deadbeef" {
+		t.Errorf("expected interpolated header, got '%s'", rendered.Headers[0].Value())
+	}
+}
+
+// TestInterpolateRequestBodySpec ensures a variable extracted from an
+// earlier request in a chain (see DependsOn) can be fed into the newer
+// BodySpec forms, not just the legacy plain-string Body.
+func TestInterpolateRequestBodySpec(t *testing.T) {
+	vars := map[string]string{"token": "deadbeef"}
+
+	tests := []struct {
+		name string
+		spec bodySpec
+		want bodySpec
+	}{
+		{
+			name: "raw",
+			spec: bodySpec{Kind: bodyRaw, Raw: "token={{.token}}"},
+			want: bodySpec{Kind: bodyRaw, Raw: "token=deadbeef"},
+		},
+		{
+			name: "form",
+			spec: bodySpec{Kind: bodyForm, Form: map[string]string{"token": "{{.token}}"}},
+			want: bodySpec{Kind: bodyForm, Form: map[string]string{"token": "deadbeef"}},
+		},
+		{
+			name: "multipart",
+			spec: bodySpec{Kind: bodyMultipart, Multipart: []multipartField{
+				{Name: "token", Value: "{{.token}}"},
+				{Name: "file", File: "x.bin", Filename: "{{.token}}.bin"},
+			}},
+			want: bodySpec{Kind: bodyMultipart, Multipart: []multipartField{
+				{Name: "token", Value: "deadbeef"},
+				{Name: "file", File: "x.bin", Filename: "deadbeef.bin"},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		r := request{BodySpec: &test.spec}
+		rendered, err := interpolateRequest(r, vars)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		switch test.want.Kind {
+		case bodyRaw:
+			if rendered.BodySpec.Raw != test.want.Raw {
+				t.Errorf("%s: expected raw '%s', got '%s'", test.name, test.want.Raw, rendered.BodySpec.Raw)
+			}
+		case bodyForm:
+			if rendered.BodySpec.Form["token"] != test.want.Form["token"] {
+				t.Errorf("%s: expected form token '%s', got '%s'", test.name, test.want.Form["token"], rendered.BodySpec.Form["token"])
+			}
+		case bodyMultipart:
+			for i, part := range rendered.BodySpec.Multipart {
+				if part.Value != test.want.Multipart[i].Value {
+					t.Errorf("%s: part %d: expected value '%s', got '%s'", test.name, i, test.want.Multipart[i].Value, part.Value)
+				}
+				if part.Filename != test.want.Multipart[i].Filename {
+					t.Errorf("%s: part %d: expected filename '%s', got '%s'", test.name, i, test.want.Multipart[i].Filename, part.Filename)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// runLoad repeatedly fires requests (cycling through them round-robin)
+// across -concurrency workers, for either -duration or -count requests per
+// worker, optionally rate-limited via -rate. Samples taken during -warmup
+// are discarded so the report reflects steady-state behaviour.
+func runLoad(requests []request, sessions map[string]*session, defaultClient *http.Client) error {
+	if len(requests) == 0 {
+		return fmt.Errorf("no requests found to load test")
+	}
+	if *flagDuration <= 0 && *flagCount <= 0 {
+		return fmt.Errorf("-load requires -duration or -count")
+	}
+	if *flagConcurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1")
+	}
+
+	var limiter *rateLimiter
+	if *flagRate > 0 {
+		var err error
+		limiter, err = newRateLimiter(*flagRate)
+		if err != nil {
+			return err
+		}
+		defer limiter.Stop()
+	}
+
+	stats := newLoadStats()
+	warmupUntil := time.Now().Add(*flagWarmup)
+
+	var stop chan struct{}
+	if *flagDuration > 0 {
+		stop = make(chan struct{})
+		time.AfterFunc(*flagDuration, func() { close(stop) })
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(*flagConcurrency)
+
+	start := time.Now()
+	for w := 0; w < *flagConcurrency; w++ {
+		go func() {
+			defer wg.Done()
+
+			for i := 0; *flagCount <= 0 || i < *flagCount; i++ {
+				if stop != nil {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+				}
+
+				if limiter != nil {
+					limiter.Wait()
+				}
+
+				req := requests[i%len(requests)]
+				sess, err := resolveSession(req, sessions, defaultClient)
+				var res result
+				if err != nil {
+					res = result{Request: req, ResponseTime: -1, Error: err}
+				} else {
+					res = send(sess, req)
+				}
+				if time.Now().After(warmupUntil) {
+					stats.record(res)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	stats.report(os.Stdout, elapsed)
+	return nil
+}
+
+// rateLimiter throttles callers to approximately rate events per second,
+// shared across however many workers call Wait.
+type rateLimiter struct {
+	ticker *time.Ticker
+}
+
+func newRateLimiter(rate float64) (*rateLimiter, error) {
+	interval := time.Duration(float64(time.Second) / rate)
+	if interval <= 0 {
+		return nil, fmt.Errorf("-rate %g is too high: computed interval is non-positive", rate)
+	}
+	return &rateLimiter{ticker: time.NewTicker(interval)}, nil
+}
+
+// Wait blocks until the next token is available.
+func (r *rateLimiter) Wait() {
+	<-r.ticker.C
+}
+
+func (r *rateLimiter) Stop() {
+	r.ticker.Stop()
+}
+
+// loadStats accumulates latency samples and status-code/error counts across
+// concurrent workers.
+type loadStats struct {
+	mu           sync.Mutex
+	latencies    []time.Duration
+	statusCounts map[int]int
+	errors       int
+	total        int
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{statusCounts: map[int]int{}}
+}
+
+func (s *loadStats) record(res result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total++
+	if res.Error != nil {
+		s.errors++
+		return
+	}
+	s.latencies = append(s.latencies, res.ResponseTime)
+	s.statusCounts[res.StatusCode]++
+}
+
+// report prints request/error counts, throughput, a latency histogram
+// (p50/p90/p99/max) and the status-code distribution to w.
+func (s *loadStats) report(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	latencies := append([]time.Duration(nil), s.latencies...)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	percentile := func(p float64) time.Duration {
+		if len(latencies) == 0 {
+			return 0
+		}
+		idx := int(p * float64(len(latencies)))
+		if idx >= len(latencies) {
+			idx = len(latencies) - 1
+		}
+		return latencies[idx]
+	}
+
+	var max time.Duration
+	if len(latencies) > 0 {
+		max = latencies[len(latencies)-1]
+	}
+
+	errRate := 0.0
+	if s.total > 0 {
+		errRate = 100 * float64(s.errors) / float64(s.total)
+	}
+
+	fmt.Fprintf(w, "requests: %d, errors: %d (%.2f%%), duration: %s, throughput: %.2f req/s\n",
+		s.total, s.errors, errRate, elapsed.Round(time.Millisecond), float64(s.total)/elapsed.Seconds())
+	fmt.Fprintf(w, "latency:  p50=%s p90=%s p99=%s max=%s\n",
+		percentile(0.50), percentile(0.90), percentile(0.99), max)
+
+	fmt.Fprintln(w, "status codes:")
+	codes := make([]int, 0, len(s.statusCounts))
+	for code := range s.statusCounts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "  %d: %d\n", code, s.statusCounts[code])
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadStatsRecordAndReport(t *testing.T) {
+	stats := newLoadStats()
+	stats.record(result{StatusCode: 200, ResponseTime: 10 * time.Millisecond})
+	stats.record(result{StatusCode: 200, ResponseTime: 20 * time.Millisecond})
+	stats.record(result{Error: fmt.Errorf("boom")})
+
+	buf := &bytes.Buffer{}
+	stats.report(buf, time.Second)
+
+	out := buf.String()
+	if !strings.Contains(out, "requests: 3, errors: 1") {
+		t.Errorf("expected requests/errors summary, got: %s", out)
+	}
+	if !strings.Contains(out, "200: 2") {
+		t.Errorf("expected status code breakdown, got: %s", out)
+	}
+}
+
+func TestNewRateLimiterRejectsNonPositiveInterval(t *testing.T) {
+	if _, err := newRateLimiter(1e18); err == nil {
+		t.Error("expecting error for a rate that yields a non-positive ticker interval, got none")
+	}
+}
+
+func TestNewRateLimiterValid(t *testing.T) {
+	limiter, err := newRateLimiter(1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer limiter.Stop()
+	limiter.Wait()
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"path"
-	"strings"
 	"time"
 )
 
@@ -27,19 +30,44 @@ func (n *noopWriter) Write(p []byte) (int, error) {
 
 // Program flags.
 var (
-	flagDebug = flag.Bool("debug", false, "enable debugging/verbosity")
-	flagDir   = flag.String("dir", ".", "directory with request files")
+	flagDebug     = flag.Bool("debug", false, "enable debugging/verbosity")
+	flagDir       = flag.String("dir", ".", "directory with request files")
+	flagReport    = flag.String("report", "text", "report format: text|json|junit|tap")
+	flagReportOut = flag.String("report-out", "", "file to write the report to (default stdout)")
+	flagEnv       = flag.String("env", "", "path to a session/environment file (default: session.json in -dir)")
+
+	flagLoad        = flag.Bool("load", false, "enable load-testing mode: repeatedly fire the scanned requests")
+	flagConcurrency = flag.Int("concurrency", 1, "number of concurrent workers in load mode")
+	flagRate        = flag.Float64("rate", 0, "requests per second to issue in load mode, across all workers (0 = unlimited)")
+	flagDuration    = flag.Duration("duration", 0, "how long to run the load test for, e.g. 30s (alternative to -count)")
+	flagCount       = flag.Int("count", 0, "number of requests each worker issues in load mode (alternative to -duration)")
+	flagWarmup      = flag.Duration("warmup", 0, "warmup period before load-test samples are recorded, e.g. 5s")
 )
 
 var (
 	debug = log.New(&noopWriter{}, "DEBUG ", log.LstdFlags)
 )
 
+// timing breaks a request's response time down by phase, as observed via
+// net/http/httptrace.
+type timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration // time to first response byte
+	Total   time.Duration
+}
+
 type result struct {
-	Request      request       // The initial request information to send.
-	Response     string        // response as a string
-	ResponseTime time.Duration // response time
-	Error        error         // possible error
+	Request         request           // The initial request information to send.
+	Response        string            // response as a string
+	ResponseTime    time.Duration     // response time
+	Error           error             // possible error
+	Vars            map[string]string // variables extracted from the response, for dependants
+	StatusCode      int               // HTTP status code of the response, if any was received
+	ResponseHeaders http.Header       // headers of the response, if any was received
+	BytesReceived   int               // number of response body bytes received
+	Timing          timing            // response time, broken down by phase
 }
 
 // String returns a string representation of the result.
@@ -115,90 +143,157 @@ func scanDirectory(d string) ([]request, error) {
 	return requests, nil
 }
 
-// send sends an HTTP request using the given request object r. The result is sent
-// to the given channel c.
-func send(r request, c chan result) {
-	client := http.Client{}
-	reader := strings.NewReader(r.Body)
-	req, err := http.NewRequest(r.Method, r.URL, reader)
-	if err != nil {
-		c <- result{r, "", -1, err}
-		return
+// newHTTPClient builds the shared http.Client used to send requests. Its
+// Transport is reused across calls to send so TCP connections (and,
+// crucially for -load, TLS sessions) are kept alive and pooled rather than
+// rebuilt per request.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: 64,
+			IdleConnTimeout:     90 * time.Second,
+		},
 	}
+}
 
-	// This block enables us to timeout the HTTP call.
-	type response struct {
-		Resp *http.Response
-		Err  error
+// send sends an HTTP request using the given request object r against
+// sess's client, and returns the outcome as a result. If r.Extract is set,
+// the variables it describes are extracted from the response and returned
+// on result.Vars for use by dependent requests. r.Timeout, if set, bounds
+// the whole round-trip via a context deadline. sess supplies the
+// http.Client, base URL and default headers to use (see resolveSession);
+// sess.Client should be shared across calls (see newHTTPClient).
+func send(sess *session, r request) result {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(r.Timeout)*time.Millisecond)
+		defer cancel()
 	}
-	timeoutChan := make(chan response, 1)
 
-	tstart := time.Now()
-	go func() {
-		r, err := client.Do(req)
-		timeoutChan <- response{r, err}
-	}()
-
-	timeout := time.Duration(r.Timeout) * time.Millisecond
-
-	var theResponse response
+	reqURL, err := resolveURL(sess, r.URL)
+	if err != nil {
+		return result{Request: r, ResponseTime: -1, Error: err}
+	}
 
-	select {
-	case <-time.After(timeout):
-		c <- result{r, "", -1, fmt.Errorf("timeout after %d ms", r.Timeout)}
-		return
-	case theResponse = <-timeoutChan:
+	reader, contentType, closer, err := buildRequestBody(r)
+	if err != nil {
+		return result{Request: r, ResponseTime: -1, Error: err}
+	}
+	if closer != nil {
+		defer closer.Close()
 	}
 
-	responseTime := time.Now().Sub(tstart)
+	req, err := http.NewRequestWithContext(ctx, r.Method, reqURL, reader)
+	if err != nil {
+		return result{Request: r, ResponseTime: -1, Error: err}
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for _, h := range sess.Headers {
+		if h.Name() != "" {
+			req.Header.Set(h.Name(), h.Value())
+		}
+	}
+	for _, h := range r.Headers {
+		if h.Name() != "" {
+			req.Header.Set(h.Name(), h.Value())
+		}
+	}
 
-	if theResponse.Err != nil {
-		c <- result{r, "", -1, theResponse.Err}
-		return
+	var tm timing
+	var dnsStart, connectStart, tlsStart, tstart time.Time
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { tm.DNS = time.Since(dnsStart) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { tm.Connect = time.Since(connectStart) },
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { tm.TLS = time.Since(tlsStart) },
+		GotFirstResponseByte: func() { tm.TTFB = time.Since(tstart) },
+	}))
+
+	tstart = time.Now()
+	resp, err := sess.Client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return result{Request: r, ResponseTime: -1, Error: fmt.Errorf("timeout after %d ms", r.Timeout)}
+		}
+		return result{Request: r, ResponseTime: -1, Error: err}
 	}
+	defer resp.Body.Close()
 
-	str, err := ioutil.ReadAll(theResponse.Resp.Body)
+	str, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		c <- result{r, "", -1, err}
-		return
+		return result{Request: r, ResponseTime: -1, Error: err, StatusCode: resp.StatusCode}
 	}
 
+	responseTime := time.Since(tstart)
+	tm.Total = responseTime
+
 	// Print some debugging information, if applicable.
 	if *flagDebug {
 		debug.Printf("[%s]: HTTP request:\n%s", r.Name, r.Body)
 		for _, k := range r.Headers {
 			debug.Printf("[%s]: HTTP request header: %s\n", r.Name, k)
 		}
-		for k, v := range theResponse.Resp.Header {
+		for k, v := range resp.Header {
 			debug.Printf("[%s]: HTTP response header: %s=%s\n", r.Name, k, v[0])
 		}
 		debug.Printf("[%s]: HTTP response:\n%s", r.Name, str)
 	}
 
-	for _, assert := range r.Assertions {
-		if !assert.Find(str) {
-			c <- result{r, "", responseTime, fmt.Errorf("assertion failed: '%s'", assert)}
-			return
-		}
+	if err := evaluateAll(r.Assertions, resp, str, responseTime); err != nil {
+		return result{Request: r, ResponseTime: responseTime, Error: err, StatusCode: resp.StatusCode, ResponseHeaders: resp.Header, BytesReceived: len(str), Timing: tm}
+	}
+
+	vars, err := extractAll(r.Extract, resp, str)
+	if err != nil {
+		return result{Request: r, ResponseTime: responseTime, Error: err, StatusCode: resp.StatusCode, ResponseHeaders: resp.Header, BytesReceived: len(str), Timing: tm}
 	}
 
 	// Result ok, assertions matched, no error.
-	c <- result{r, string(str), responseTime, nil}
+	return result{
+		Request:         r,
+		Response:        string(str),
+		ResponseTime:    responseTime,
+		Vars:            vars,
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: resp.Header,
+		BytesReceived:   len(str),
+		Timing:          tm,
+	}
 }
 
-// run iterates over the requests, sends them to their destinations. Gather results.
-func run(requests []request) error {
-	c := make(chan result)
-	for _, request := range requests {
-		go send(request, c)
+// run builds a dependency graph from the requests' DependsOn fields and
+// fans them out: requests without dependencies start immediately, while
+// dependents block until their prerequisites have run. A prerequisite's
+// extracted variables (see request.Extract) are interpolated into its
+// dependents before they are sent; a failed prerequisite short-circuits its
+// dependents with a "skipped" result rather than sending them. All results
+// are gathered and returned once every request has run. If onResult is
+// non-nil, it is additionally invoked with each result as it arrives, so a
+// streamingReporter can print output incrementally rather than waiting for
+// the whole run to finish.
+func run(requests []request, sessions map[string]*session, defaultClient *http.Client, onResult func(result)) ([]result, error) {
+	nodes, err := buildChain(requests)
+	if err != nil {
+		return nil, err
 	}
 
-	for range requests {
-		res := <-c
-		fmt.Println(res)
+	c := make(chan result)
+	runChain(nodes, c, sessions, defaultClient)
+
+	var results []result
+	for res := range c {
+		if onResult != nil {
+			onResult(res)
+		}
+		results = append(results, res)
 	}
 
-	return nil
+	return results, nil
 }
 
 // usage prints the usage of the program.
@@ -227,5 +322,69 @@ func main() {
 		os.Exit(3)
 	}
 
-	run(requests)
+	envPath := *flagEnv
+	if envPath == "" {
+		envPath = path.Join(*flagDir, "session.json")
+	}
+	sessionConfigs, err := loadSessions(envPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to load session file '%s': %v\n", envPath, err)
+		os.Exit(3)
+	}
+	sessions, err := buildSessions(sessionConfigs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to build sessions: %v\n", err)
+		os.Exit(3)
+	}
+
+	client := newHTTPClient()
+
+	if *flagLoad {
+		if err := runLoad(requests, sessions, client); err != nil {
+			fmt.Fprintf(os.Stderr, "load test failed: %v\n", err)
+			os.Exit(3)
+		}
+		return
+	}
+
+	reporter, err := reporterFor(*flagReport)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(3)
+	}
+
+	out := io.Writer(os.Stdout)
+	if *flagReportOut != "" {
+		f, err := os.Create(*flagReportOut)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "unable to create report file '%s': %v\n", *flagReportOut, err)
+			os.Exit(3)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var onResult func(result)
+	if sr, ok := reporter.(streamingReporter); ok {
+		onResult = func(res result) { sr.ReportOne(out, res) }
+	}
+
+	results, err := run(requests, sessions, client, onResult)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "run failed: %v\n", err)
+		os.Exit(3)
+	}
+
+	if _, ok := reporter.(streamingReporter); !ok {
+		if err := reporter.Report(results, out); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write report: %v\n", err)
+			os.Exit(3)
+		}
+	}
+
+	for _, res := range results {
+		if res.Error != nil {
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reporter renders a finished set of results in a specific output format.
+type Reporter interface {
+	Report(results []result, w io.Writer) error
+}
+
+// streamingReporter is implemented by reporters that can usefully print a
+// result as soon as it arrives, rather than only once the whole run has
+// finished. textReporter implements it to preserve the original
+// print-as-results-complete behaviour; formats with a single enclosing
+// structure (JSON array, JUnit XML, TAP) can't start writing until every
+// result is known, so they don't.
+type streamingReporter interface {
+	ReportOne(w io.Writer, r result) error
+}
+
+// reporterFor returns the Reporter for the named format, as accepted by the
+// -report flag.
+func reporterFor(format string) (Reporter, error) {
+	switch format {
+	case "", "text":
+		return textReporter{}, nil
+	case "json":
+		return jsonReporter{}, nil
+	case "junit":
+		return junitReporter{}, nil
+	case "tap":
+		return tapReporter{}, nil
+	}
+	return nil, fmt.Errorf("unknown report format '%s'", format)
+}
+
+// isAssertionFailure reports whether err came from a failed assertion, as
+// opposed to a transport-level error such as a timeout or connection
+// failure.
+func isAssertionFailure(err error) bool {
+	var af *assertionFailure
+	return errors.As(err, &af)
+}
+
+//============================================================================
+
+// textReporter reproduces the original plain-text, one-line-per-result output.
+type textReporter struct{}
+
+func (textReporter) Report(results []result, w io.Writer) error {
+	for _, r := range results {
+		fmt.Fprintln(w, r)
+	}
+	return nil
+}
+
+// ReportOne prints a single result, in the same format as Report, as soon
+// as it becomes available.
+func (textReporter) ReportOne(w io.Writer, r result) error {
+	_, err := fmt.Fprintln(w, r)
+	return err
+}
+
+//============================================================================
+
+// jsonResult is the JSON representation of a single result.
+type jsonResult struct {
+	Name            string              `json:"name"`
+	URL             string              `json:"url"`
+	Method          string              `json:"method"`
+	OK              bool                `json:"ok"`
+	Error           string              `json:"error,omitempty"`
+	StatusCode      int                 `json:"statusCode"`
+	ResponseTimeMs  int64               `json:"responseTimeMs"`
+	BytesReceived   int                 `json:"bytesReceived"`
+	ResponseHeaders map[string][]string `json:"responseHeaders,omitempty"`
+	Timing          jsonTiming          `json:"timing"`
+}
+
+type jsonTiming struct {
+	DNSMs     int64 `json:"dnsMs"`
+	ConnectMs int64 `json:"connectMs"`
+	TLSMs     int64 `json:"tlsMs"`
+	TTFBMs    int64 `json:"ttfbMs"`
+	TotalMs   int64 `json:"totalMs"`
+}
+
+type jsonReporter struct{}
+
+func (jsonReporter) Report(results []result, w io.Writer) error {
+	out := make([]jsonResult, 0, len(results))
+	for _, r := range results {
+		jr := jsonResult{
+			Name:            r.Request.Name,
+			URL:             r.Request.URL,
+			Method:          r.Request.Method,
+			OK:              r.Error == nil,
+			StatusCode:      r.StatusCode,
+			ResponseTimeMs:  r.ResponseTime.Milliseconds(),
+			BytesReceived:   r.BytesReceived,
+			ResponseHeaders: r.ResponseHeaders,
+			Timing: jsonTiming{
+				DNSMs:     r.Timing.DNS.Milliseconds(),
+				ConnectMs: r.Timing.Connect.Milliseconds(),
+				TLSMs:     r.Timing.TLS.Milliseconds(),
+				TTFBMs:    r.Timing.TTFB.Milliseconds(),
+				TotalMs:   r.Timing.Total.Milliseconds(),
+			},
+		}
+		if r.Error != nil {
+			jr.Error = r.Error.Error()
+		}
+		out = append(out, jr)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+//============================================================================
+
+// junitTestsuite and junitTestcase model just enough of the JUnit XML
+// schema for CI systems (Jenkins, GitLab, GitHub Actions) to render results.
+type junitTestsuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      float64         `xml:"time,attr"`
+	Testcases []junitTestcase `xml:"testcase"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitOutcome `xml:"failure,omitempty"`
+	Error     *junitOutcome `xml:"error,omitempty"`
+}
+
+type junitOutcome struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitReporter struct{}
+
+func (junitReporter) Report(results []result, w io.Writer) error {
+	suite := junitTestsuite{Tests: len(results)}
+
+	for _, r := range results {
+		tc := junitTestcase{
+			Name:      r.Request.Name,
+			Classname: "warden",
+			Time:      r.ResponseTime.Seconds(),
+		}
+		suite.Time += tc.Time
+
+		if r.Error != nil {
+			outcome := &junitOutcome{Message: r.Error.Error(), Content: r.Error.Error()}
+			if isAssertionFailure(r.Error) {
+				tc.Failure = outcome
+				suite.Failures++
+			} else {
+				tc.Error = outcome
+				suite.Errors++
+			}
+		}
+
+		suite.Testcases = append(suite.Testcases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+//============================================================================
+
+// tapReporter emits Test Anything Protocol output: one "ok"/"not ok" line
+// per result, with a YAML diagnostic block for failures.
+type tapReporter struct{}
+
+func (tapReporter) Report(results []result, w io.Writer) error {
+	fmt.Fprintf(w, "1..%d\n", len(results))
+
+	for i, r := range results {
+		status := "ok"
+		if r.Error != nil {
+			status = "not ok"
+		}
+		fmt.Fprintf(w, "%s %d - %s\n", status, i+1, r.Request.Name)
+
+		if r.Error != nil {
+			fmt.Fprintln(w, "  ---")
+			fmt.Fprintf(w, "  message: %s\n", tapQuote(r.Error.Error()))
+			fmt.Fprintln(w, "  ...")
+		}
+	}
+
+	return nil
+}
+
+// tapQuote renders s as a single-quoted YAML scalar, as used in TAP
+// diagnostic blocks.
+func tapQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
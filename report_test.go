@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReporterForKnownFormats(t *testing.T) {
+	for _, format := range []string{"", "text", "json", "junit", "tap"} {
+		if _, err := reporterFor(format); err != nil {
+			t.Errorf("format '%s': unexpected error: %v", format, err)
+		}
+	}
+	if _, err := reporterFor("bogus"); err == nil {
+		t.Error("expecting error for unknown format, got none")
+	}
+}
+
+func TestJSONReporterIncludesStatusCode(t *testing.T) {
+	results := []result{
+		{Request: request{Name: "ok"}, StatusCode: 201, ResponseTime: 5 * time.Millisecond},
+		{Request: request{Name: "failed"}, StatusCode: 500, Error: &assertionFailure{msg: "boom"}},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := (jsonReporter{}).Report(results, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded []jsonResult
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed decoding report output: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(decoded))
+	}
+	if decoded[0].StatusCode != 201 {
+		t.Errorf("expected statusCode 201, got %d", decoded[0].StatusCode)
+	}
+	if decoded[0].OK != true {
+		t.Error("expected first result to be OK")
+	}
+	if decoded[1].StatusCode != 500 || decoded[1].OK {
+		t.Errorf("expected second result to be statusCode 500/not OK, got %+v", decoded[1])
+	}
+}
+
+func TestJUnitReporterCountsFailuresAndErrors(t *testing.T) {
+	results := []result{
+		{Request: request{Name: "passes"}},
+		{Request: request{Name: "assertion-fails"}, Error: &assertionFailure{msg: "assertion failed"}},
+		{Request: request{Name: "transport-error"}, Error: fmt.Errorf("connection refused")},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := (junitReporter{}).Report(results, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `tests="3"`) {
+		t.Errorf("expected tests=\"3\" in output, got: %s", out)
+	}
+	if !strings.Contains(out, `failures="1"`) {
+		t.Errorf("expected failures=\"1\" in output, got: %s", out)
+	}
+	if !strings.Contains(out, `errors="1"`) {
+		t.Errorf("expected errors=\"1\" in output, got: %s", out)
+	}
+}
+
+func TestTAPReporterFormatsOkAndNotOk(t *testing.T) {
+	results := []result{
+		{Request: request{Name: "passes"}},
+		{Request: request{Name: "fails"}, Error: fmt.Errorf("boom")},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := (tapReporter{}).Report(results, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "1..2" {
+		t.Errorf("expected plan '1..2', got '%s'", lines[0])
+	}
+	if lines[1] != "ok 1 - passes" {
+		t.Errorf("expected 'ok 1 - passes', got '%s'", lines[1])
+	}
+	if lines[2] != "not ok 2 - fails" {
+		t.Errorf("expected 'not ok 2 - fails', got '%s'", lines[2])
+	}
+}
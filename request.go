@@ -13,6 +13,10 @@ import (
 // request is the struct representing a request file, with JSON front-matter
 // plus the Body content.
 type request struct {
+	// Name identifies this request so other requests can depend on it via
+	// DependsOn. It is optional, but if set it must be unique among the
+	// other named requests in the same run (see buildChain); unnamed
+	// requests, which can never be a dependency target, are exempt.
 	Name       string
 	URL        string
 	Method     string
@@ -20,6 +24,27 @@ type request struct {
 	Headers    []header
 	Assertions []assertion
 
+	// Extract maps a variable name to an extractor expression which is
+	// evaluated against this request's response. The resulting values become
+	// available for interpolation (via {{.name}}) in requests which declare
+	// this request in their DependsOn list. Supported expression forms are
+	// documented on extractValue.
+	Extract map[string]string
+
+	// DependsOn lists the Name of requests which must run, and succeed,
+	// before this request is sent. The runner builds a DAG from this field.
+	DependsOn []string
+
+	// BodySpec, when set via a "body" key in the front-matter, overrides
+	// Body with a richer encoding (raw, file, form or multipart). See
+	// bodySpec for the supported forms.
+	BodySpec *bodySpec `json:"body"`
+
+	// Session names an entry from the environment's session file (see
+	// sessions.go) whose client, base URL and default headers this request
+	// should use. Empty means no session.
+	Session string
+
 	Body string
 }
 
@@ -47,23 +72,244 @@ func (h header) Value() string {
 
 //============================================================================
 
-// assertion is a custom string type with some methods for validating and
-// executing assertions.
-type assertion string
+// Assertion kinds, as recognized by assertion.UnmarshalJSON.
+const (
+	assertionBodyRegex       = "body_regex"
+	assertionStatus          = "status"
+	assertionHeader          = "header"
+	assertionJSONPath        = "jsonpath"
+	assertionJSONSchema      = "json_schema"
+	assertionResponseTimeMax = "response_time_max_ms"
+)
+
+// headerAssertion checks a single response header, either against a regex or
+// for an exact match. Exactly one of Regex or Equals should be set.
+type headerAssertion struct {
+	Name   string `json:"name"`
+	Regex  string `json:"regex"`
+	Equals string `json:"equals"`
+}
+
+// jsonPathAssertion checks a dotted JSON path (see jsonPathLookup) in the
+// response body. If Equals is nil, the assertion only checks the path
+// exists.
+type jsonPathAssertion struct {
+	Path   string  `json:"path"`
+	Equals *string `json:"equals"`
+}
+
+// jsonSchemaAssertion validates the response body against a schema, given
+// either inline or as a path to a file. See validateSchema for the subset
+// of JSON Schema that is supported.
+type jsonSchemaAssertion struct {
+	Schema json.RawMessage `json:"schema"`
+	File   string          `json:"file"`
+}
+
+// assertion is a single check to run against a response. Its Kind
+// determines which of the other fields are populated. A bare JSON string
+// unmarshals as a body_regex assertion, for backwards compatibility with
+// the original regex-only assertions.
+type assertion struct {
+	Kind string
+
+	BodyRegex         string
+	Statuses          []int
+	Header            headerAssertion
+	JSONPath          jsonPathAssertion
+	JSONSchema        jsonSchemaAssertion
+	ResponseTimeMaxMs int64
+}
+
+// String returns the assertion in a form suitable for error and debug messages.
+func (a assertion) String() string {
+	switch a.Kind {
+	case assertionBodyRegex:
+		return fmt.Sprintf("body_regex '%s'", a.BodyRegex)
+	case assertionStatus:
+		return fmt.Sprintf("status %v", a.Statuses)
+	case assertionHeader:
+		return fmt.Sprintf("header '%s'", a.Header.Name)
+	case assertionJSONPath:
+		return fmt.Sprintf("jsonpath '%s'", a.JSONPath.Path)
+	case assertionJSONSchema:
+		return "json_schema"
+	case assertionResponseTimeMax:
+		return fmt.Sprintf("response_time_max_ms %d", a.ResponseTimeMaxMs)
+	}
+	return "unknown assertion"
+}
+
+// UnmarshalJSON decodes an assertion. A bare JSON string is treated as a
+// body_regex assertion; otherwise exactly one of the recognized kind keys
+// (status, header, body_regex, jsonpath, json_schema,
+// response_time_max_ms) must be present.
+func (a *assertion) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		a.Kind = assertionBodyRegex
+		a.BodyRegex = s
+		return nil
+	}
+
+	var raw struct {
+		Status            json.RawMessage      `json:"status"`
+		Header            *headerAssertion     `json:"header"`
+		BodyRegex         *string              `json:"body_regex"`
+		JSONPath          *jsonPathAssertion   `json:"jsonpath"`
+		JSONSchema        *jsonSchemaAssertion `json:"json_schema"`
+		ResponseTimeMaxMs *int64               `json:"response_time_max_ms"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("assertion: %v", err)
+	}
+
+	kinds := 0
+	for _, present := range []bool{raw.Status != nil, raw.Header != nil, raw.BodyRegex != nil,
+		raw.JSONPath != nil, raw.JSONSchema != nil, raw.ResponseTimeMaxMs != nil} {
+		if present {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("assertion: more than one kind in %s", data)
+	}
+
+	switch {
+	case raw.Status != nil:
+		a.Kind = assertionStatus
+		// Accept either a single status code, or a list of them.
+		var single int
+		if err := json.Unmarshal(raw.Status, &single); err == nil {
+			a.Statuses = []int{single}
+			return nil
+		}
+		if err := json.Unmarshal(raw.Status, &a.Statuses); err != nil {
+			return fmt.Errorf("assertion: status must be an int or a list of ints: %v", err)
+		}
+	case raw.Header != nil:
+		a.Kind = assertionHeader
+		a.Header = *raw.Header
+	case raw.BodyRegex != nil:
+		a.Kind = assertionBodyRegex
+		a.BodyRegex = *raw.BodyRegex
+	case raw.JSONPath != nil:
+		a.Kind = assertionJSONPath
+		a.JSONPath = *raw.JSONPath
+	case raw.JSONSchema != nil:
+		a.Kind = assertionJSONSchema
+		a.JSONSchema = *raw.JSONSchema
+	case raw.ResponseTimeMaxMs != nil:
+		a.Kind = assertionResponseTimeMax
+		a.ResponseTimeMaxMs = *raw.ResponseTimeMaxMs
+	default:
+		return fmt.Errorf("assertion: no recognized kind in %s", data)
+	}
 
-// Validate validates the assertion by compiling it, and returning the error if any.
+	return nil
+}
+
+// Validate checks that the assertion is internally consistent (e.g. that a
+// body_regex or header regex compiles), returning an error describing the
+// first problem found, or nil.
 func (a assertion) Validate() error {
-	_, err := regexp.Compile(string(a))
-	return fmt.Errorf("assertion regexp '%s' cannot be compiled: %v", a, err)
+	switch a.Kind {
+	case assertionBodyRegex:
+		if _, err := regexp.Compile(a.BodyRegex); err != nil {
+			return fmt.Errorf("assertion regexp '%s' cannot be compiled: %v", a.BodyRegex, err)
+		}
+	case assertionHeader:
+		if a.Header.Regex != "" {
+			if _, err := regexp.Compile(a.Header.Regex); err != nil {
+				return fmt.Errorf("header assertion regexp '%s' cannot be compiled: %v", a.Header.Regex, err)
+			}
+		}
+	}
+	return nil
 }
 
-// Find compiles the assertion string and tries to find it in the given bytes content.
-// Will return true if the content is found
-func (a assertion) Find(content []byte) bool {
-	// Validate() should be used during startup to check whether the configuration
-	// file is correct. So at this point it should be good.
-	re := regexp.MustCompile(string(a))
-	return re.Find(content) != nil
+//============================================================================
+
+// Body kinds, as recognized by bodySpec.UnmarshalJSON.
+const (
+	bodyRaw       = "raw"
+	bodyFile      = "file"
+	bodyForm      = "form"
+	bodyMultipart = "multipart"
+)
+
+// multipartField describes one part of a multipart/form-data body. A part
+// with File set is streamed from disk as a file upload; otherwise Value is
+// sent as a plain form field.
+type multipartField struct {
+	Name        string `json:"name"`
+	Value       string `json:"value,omitempty"`
+	File        string `json:"file,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+// bodySpec is a richer alternative to the plain-string Body, set via a
+// "body" key in the front-matter. Its Kind determines which of the other
+// fields are populated. A bare JSON string unmarshals as a raw body, for
+// parity with the plain-string form.
+type bodySpec struct {
+	Kind string
+
+	Raw       string
+	File      string
+	Form      map[string]string
+	Multipart []multipartField
+}
+
+// UnmarshalJSON decodes a bodySpec. A bare JSON string is treated as a raw
+// body; otherwise exactly one of raw, file, form or multipart must be present.
+func (b *bodySpec) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		b.Kind = bodyRaw
+		b.Raw = s
+		return nil
+	}
+
+	var raw struct {
+		Raw       *string           `json:"raw"`
+		File      *string           `json:"file"`
+		Form      map[string]string `json:"form"`
+		Multipart []multipartField  `json:"multipart"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("body: %v", err)
+	}
+
+	kinds := 0
+	for _, present := range []bool{raw.Raw != nil, raw.File != nil, raw.Form != nil, raw.Multipart != nil} {
+		if present {
+			kinds++
+		}
+	}
+	if kinds > 1 {
+		return fmt.Errorf("body: more than one kind in %s", data)
+	}
+
+	switch {
+	case raw.Raw != nil:
+		b.Kind = bodyRaw
+		b.Raw = *raw.Raw
+	case raw.File != nil:
+		b.Kind = bodyFile
+		b.File = *raw.File
+	case raw.Form != nil:
+		b.Kind = bodyForm
+		b.Form = raw.Form
+	case raw.Multipart != nil:
+		b.Kind = bodyMultipart
+		b.Multipart = raw.Multipart
+	default:
+		return fmt.Errorf("body: no recognized kind in %s", data)
+	}
+
+	return nil
 }
 
 //============================================================================
@@ -132,5 +378,11 @@ func newRequest(rd io.Reader) (request, error) {
 	}
 	req.Body = string(writerBody.String())
 
+	for _, a := range req.Assertions {
+		if err := a.Validate(); err != nil {
+			return request{}, fmt.Errorf("assertion %s: %v", a, err)
+		}
+	}
+
 	return req, nil
 }
@@ -72,6 +72,24 @@ func TestNewRequestNoBody(t *testing.T) {
 	_ = req
 }
 
+// Tests that a request with an uncompilable assertion regex is rejected at
+// parse time, rather than only failing once it is sent.
+func TestNewRequestInvalidAssertionRegex(t *testing.T) {
+	doc := `{
+    "name": "bad assertion",
+    "url": "http://example.org",
+    "method": "GET",
+    "assertions": ["(unterminated"]
+}
+---`
+
+	reader := strings.NewReader(doc)
+	_, err := newRequest(reader)
+	if err == nil {
+		t.Error("expecting error for an uncompilable assertion regex, got none")
+	}
+}
+
 // Tests a request with a front matter divider, but without any content.
 func TestNewRequestNoFrontMatterWithDivider(t *testing.T) {
 	doc := `---
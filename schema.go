@@ -0,0 +1,84 @@
+package main
+
+import "fmt"
+
+// miniSchema is a deliberately small subset of JSON Schema: object/array/
+// string/number/integer/boolean/null typing, required properties, and
+// recursion into nested object properties. It covers the common "does this
+// response look like X" checks without pulling in a full schema validator.
+type miniSchema struct {
+	Type       string                `json:"type"`
+	Required   []string              `json:"required"`
+	Properties map[string]miniSchema `json:"properties"`
+}
+
+// validateSchema checks instance against schema, returning a description of
+// every violation found. path is the JSON-path-like location of instance,
+// used to build readable messages; callers validating a root value pass "".
+func validateSchema(instance interface{}, schema miniSchema, path string) []string {
+	var errs []string
+
+	if schema.Type != "" && !schemaTypeMatches(schema.Type, instance) {
+		errs = append(errs, fmt.Sprintf("%s: expected type '%s', got %T", schemaLabel(path), schema.Type, instance))
+		return errs
+	}
+
+	if len(schema.Required) > 0 || len(schema.Properties) > 0 {
+		obj, ok := instance.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: expected an object", schemaLabel(path)))
+			return errs
+		}
+
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required property '%s'", schemaLabel(path), name))
+			}
+		}
+
+		for name, sub := range schema.Properties {
+			if v, ok := obj[name]; ok {
+				errs = append(errs, validateSchema(v, sub, path+"."+name)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+// schemaLabel formats path for use in an error message.
+func schemaLabel(path string) string {
+	if path == "" {
+		return "$"
+	}
+	return "$" + path
+}
+
+// schemaTypeMatches reports whether v is a JSON value of the given schema type.
+func schemaTypeMatches(typ string, v interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	default:
+		// Unknown type keyword: don't fail the assertion over it.
+		return true
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestValidateSchemaTypes(t *testing.T) {
+	tests := []struct {
+		typ     string
+		value   interface{}
+		matches bool
+	}{
+		{"string", "hi", true},
+		{"string", 1.0, false},
+		{"number", 1.5, true},
+		{"integer", 2.0, true},
+		{"integer", 2.5, false},
+		{"boolean", true, true},
+		{"object", map[string]interface{}{}, true},
+		{"array", []interface{}{}, true},
+		{"null", nil, true},
+	}
+
+	for _, test := range tests {
+		errs := validateSchema(test.value, miniSchema{Type: test.typ}, "")
+		if test.matches && len(errs) != 0 {
+			t.Errorf("type '%s' value %v: expected no errors, got %v", test.typ, test.value, errs)
+		}
+		if !test.matches && len(errs) == 0 {
+			t.Errorf("type '%s' value %v: expected a type mismatch error, got none", test.typ, test.value)
+		}
+	}
+}
+
+func TestValidateSchemaRequiredAndNested(t *testing.T) {
+	schema := miniSchema{
+		Type:     "object",
+		Required: []string{"id", "name"},
+		Properties: map[string]miniSchema{
+			"id": {Type: "integer"},
+		},
+	}
+
+	instance := map[string]interface{}{"id": 1.0}
+	errs := validateSchema(instance, schema, "")
+	if len(errs) != 1 {
+		t.Fatalf("expected exactly one error for the missing 'name' property, got %v", errs)
+	}
+
+	instance = map[string]interface{}{"id": "not-an-int", "name": "bob"}
+	errs = validateSchema(instance, schema, "")
+	if len(errs) != 1 {
+		t.Fatalf("expected exactly one error for the wrong-typed 'id', got %v", errs)
+	}
+
+	instance = map[string]interface{}{"id": 1.0, "name": "bob"}
+	if errs := validateSchema(instance, schema, ""); len(errs) != 0 {
+		t.Errorf("expected no errors for a valid instance, got %v", errs)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionConfig is the on-disk shape of a single named session, as found in
+// an environment file (see loadSessions).
+type sessionConfig struct {
+	BaseURL  string   `json:"baseUrl"`
+	Headers  []header `json:"headers"`
+	Cookies  string   `json:"cookies"`  // "persist", or empty for no cookie jar
+	Redirect string   `json:"redirect"` // "follow" (default), "none", or "max=N"
+	Proxy    string   `json:"proxy"`
+	TLS      struct {
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+		ClientCert         string `json:"client_cert"`
+		ClientKey          string `json:"client_key"`
+		CABundle           string `json:"ca_bundle"`
+	} `json:"tls"`
+}
+
+// session is a sessionConfig resolved into a usable http.Client, along with
+// the base URL and default headers requests using it should apply.
+type session struct {
+	Name    string
+	BaseURL string
+	Headers []header
+	Client  *http.Client
+}
+
+// loadSessions reads a JSON object of name -> sessionConfig from path. A
+// missing file is not an error: it yields an empty set of sessions, so
+// -env/session.json is entirely optional.
+func loadSessions(path string) (map[string]sessionConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]sessionConfig{}, nil
+		}
+		return nil, err
+	}
+
+	var configs map[string]sessionConfig
+	if err := json.Unmarshal(b, &configs); err != nil {
+		return nil, fmt.Errorf("parsing session file '%s': %v", path, err)
+	}
+	return configs, nil
+}
+
+// buildSessions resolves every sessionConfig in configs into a usable
+// session, constructing one http.Client per session with its own redirect
+// policy and TLS configuration, plus its own cookie jar when the session's
+// cookies setting is "persist".
+func buildSessions(configs map[string]sessionConfig) (map[string]*session, error) {
+	sessions := make(map[string]*session, len(configs))
+	for name, cfg := range configs {
+		s, err := buildSession(name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("session '%s': %v", name, err)
+		}
+		sessions[name] = s
+	}
+	return sessions, nil
+}
+
+func buildSession(name string, cfg sessionConfig) (*session, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLS.InsecureSkipVerify}
+
+	if cfg.TLS.CABundle != "" {
+		pem, err := os.ReadFile(cfg.TLS.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca_bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_bundle '%s' contains no certificates", cfg.TLS.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLS.ClientCert != "" || cfg.TLS.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.ClientCert, cfg.TLS.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client_cert/client_key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := &http.Transport{
+		MaxIdleConnsPerHost: 64,
+		IdleConnTimeout:     90 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	client := &http.Client{Transport: transport}
+
+	if cfg.Cookies == "persist" {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		client.Jar = jar
+	}
+
+	redirectCheck, err := redirectPolicy(cfg.Redirect)
+	if err != nil {
+		return nil, err
+	}
+	client.CheckRedirect = redirectCheck
+
+	return &session{Name: name, BaseURL: cfg.BaseURL, Headers: cfg.Headers, Client: client}, nil
+}
+
+// redirectPolicy translates the "follow" (default)/"none"/"max=N" redirect
+// setting into an http.Client.CheckRedirect function.
+func redirectPolicy(policy string) (func(req *http.Request, via []*http.Request) error, error) {
+	switch {
+	case policy == "" || policy == "follow":
+		return nil, nil // nil means "use net/http's default of up to 10 redirects"
+
+	case policy == "none":
+		return func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}, nil
+
+	case strings.HasPrefix(policy, "max="):
+		max, err := strconv.Atoi(strings.TrimPrefix(policy, "max="))
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect policy '%s': %v", policy, err)
+		}
+		return func(req *http.Request, via []*http.Request) error {
+			if len(via) >= max {
+				return fmt.Errorf("stopped after %d redirects", max)
+			}
+			return nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unrecognized redirect policy '%s'", policy)
+}
+
+// resolveSession looks up the session named by r.Session, falling back to a
+// bare session wrapping defaultClient when r declares no session. It is an
+// error for r to name a session that isn't among sessions, since silently
+// falling back would run the request without the base URL, cookies or TLS
+// config the caller asked for.
+func resolveSession(r request, sessions map[string]*session, defaultClient *http.Client) (*session, error) {
+	if r.Session == "" {
+		return &session{Client: defaultClient}, nil
+	}
+	s, ok := sessions[r.Session]
+	if !ok {
+		return nil, fmt.Errorf("request '%s' references unknown session '%s'", r.Name, r.Session)
+	}
+	return s, nil
+}
+
+// resolveURL joins raw onto sess.BaseURL when raw is a relative reference;
+// an absolute raw URL, or a session without a BaseURL, is returned as-is.
+func resolveURL(sess *session, raw string) (string, error) {
+	if sess.BaseURL == "" {
+		return raw, nil
+	}
+
+	ref, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid request url: %v", err)
+	}
+	if ref.IsAbs() {
+		return raw, nil
+	}
+
+	base, err := url.Parse(sess.BaseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid session base url: %v", err)
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolveSessionNoSessionRequested(t *testing.T) {
+	defaultClient := &http.Client{}
+	r := request{Name: "req"}
+
+	sess, err := resolveSession(r, map[string]*session{}, defaultClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess.Client != defaultClient {
+		t.Error("expected the bare session to wrap defaultClient")
+	}
+}
+
+func TestResolveSessionKnown(t *testing.T) {
+	want := &session{Name: "staging", BaseURL: "https://staging.example.org"}
+	r := request{Name: "req", Session: "staging"}
+
+	sess, err := resolveSession(r, map[string]*session{"staging": want}, &http.Client{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sess != want {
+		t.Error("expected the named session to be returned")
+	}
+}
+
+func TestResolveSessionUnknownIsError(t *testing.T) {
+	r := request{Name: "req", Session: "stageing"}
+
+	_, err := resolveSession(r, map[string]*session{"staging": {}}, &http.Client{})
+	if err == nil {
+		t.Fatal("expecting error for unknown session name, got none")
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	if fn, err := redirectPolicy(""); err != nil || fn != nil {
+		t.Errorf("expected nil func and no error for default policy, got (func set: %v) / %v", fn != nil, err)
+	}
+
+	fn, err := redirectPolicy("none")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := fn(nil, nil); err != http.ErrUseLastResponse {
+		t.Errorf("expected http.ErrUseLastResponse, got %v", err)
+	}
+
+	fn, err = redirectPolicy("max=2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := fn(nil, make([]*http.Request, 1)); err != nil {
+		t.Errorf("expected redirect 2 of max 2 to be allowed, got %v", err)
+	}
+	if err := fn(nil, make([]*http.Request, 2)); err == nil {
+		t.Error("expected redirect beyond max=2 to be rejected")
+	}
+
+	if _, err := redirectPolicy("bogus"); err == nil {
+		t.Error("expecting error for unrecognized redirect policy, got none")
+	}
+}